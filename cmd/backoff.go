@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff - pluggable retry-interval policy used by the breaker (and later
+// by anything else that needs to wait-and-retry against the database).
+type Backoff interface {
+	// NextInterval returns how long to wait before the given attempt
+	// (attempt is 1-indexed). A negative duration means "stop retrying".
+	NextInterval(attempt uint) time.Duration
+	// Reset clears any accumulated state so the policy can be reused.
+	Reset()
+}
+
+// ExponentialBackoff - an exponential backoff with full jitter, matching the
+// semantics of cenkalti/backoff's ExponentialBackOff.
+type ExponentialBackoff struct {
+	InitialInterval     time.Duration
+	MaxInterval         time.Duration
+	Multiplier          float64
+	RandomizationFactor float64
+	MaxElapsedTime      time.Duration
+
+	startedAt time.Time
+	// now - overridable in tests so MaxElapsedTime can be exercised with a
+	// fake clock instead of real sleeps. Defaults to time.Now.
+	now func() time.Time
+}
+
+// NewExponentialBackoff - returns an ExponentialBackoff configured with the
+// defaults cenkalti/backoff ships (0.5s initial, 60s max, x1.5 multiplier,
+// 0.5 randomization, 15m elapsed cap).
+func NewExponentialBackoff() *ExponentialBackoff {
+	return &ExponentialBackoff{
+		InitialInterval:     time.Millisecond * 500,
+		MaxInterval:         time.Second * 60,
+		Multiplier:          1.5,
+		RandomizationFactor: 0.5,
+		MaxElapsedTime:      time.Minute * 15,
+		now:                 time.Now,
+	}
+}
+
+// NextInterval - computes the next exponential interval with full jitter.
+func (b *ExponentialBackoff) NextInterval(attempt uint) time.Duration {
+	if b.now == nil {
+		b.now = time.Now
+	}
+	if b.startedAt.IsZero() {
+		b.startedAt = b.now()
+	}
+	if b.MaxElapsedTime > 0 && b.now().Sub(b.startedAt) > b.MaxElapsedTime {
+		return -1
+	}
+
+	exp := uint(0)
+	if attempt > 0 {
+		exp = attempt - 1
+	}
+	interval := float64(b.InitialInterval) * pow(b.Multiplier, exp)
+	if max := float64(b.MaxInterval); interval > max {
+		interval = max
+	}
+
+	delta := b.RandomizationFactor * interval
+	min := interval - delta
+	max := interval + delta
+	jittered := min + rand.Float64()*(max-min+1)
+
+	return time.Duration(jittered)
+}
+
+// Reset - clears the elapsed-time clock so MaxElapsedTime starts fresh.
+func (b *ExponentialBackoff) Reset() {
+	b.startedAt = time.Time{}
+}
+
+func pow(base float64, exp uint) float64 {
+	result := 1.0
+	for i := uint(0); i < exp; i++ {
+		result *= base
+	}
+	return result
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock - a manually-advanced clock for deterministic tests.
+type fakeClock struct {
+	t time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Unix(0, 0)}
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func TestExponentialBackoff_GrowsAndCaps(t *testing.T) {
+	b := &ExponentialBackoff{
+		InitialInterval:     time.Second,
+		MaxInterval:         time.Second * 10,
+		Multiplier:          2,
+		RandomizationFactor: 0, // disable jitter so growth is exact
+		now:                 newFakeClock().now,
+	}
+
+	first := b.NextInterval(1)
+	second := b.NextInterval(2)
+	third := b.NextInterval(3)
+	capped := b.NextInterval(10)
+
+	if first != time.Second {
+		t.Fatalf("attempt 1: got %v, want %v", first, time.Second)
+	}
+	if second != time.Second*2 {
+		t.Fatalf("attempt 2: got %v, want %v", second, time.Second*2)
+	}
+	if third <= second {
+		t.Fatalf("attempt 3 (%v) did not grow past attempt 2 (%v)", third, second)
+	}
+	if capped != b.MaxInterval {
+		t.Fatalf("large attempt: got %v, want cap %v", capped, b.MaxInterval)
+	}
+}
+
+func TestExponentialBackoff_MaxElapsedTime(t *testing.T) {
+	clock := newFakeClock()
+	b := &ExponentialBackoff{
+		InitialInterval:     time.Millisecond,
+		MaxInterval:         time.Second,
+		Multiplier:          1.5,
+		RandomizationFactor: 0,
+		MaxElapsedTime:      time.Minute,
+		now:                 clock.now,
+	}
+
+	if wait := b.NextInterval(1); wait < 0 {
+		t.Fatalf("expected a positive interval before MaxElapsedTime, got %v", wait)
+	}
+
+	clock.advance(time.Minute + time.Second)
+
+	if wait := b.NextInterval(2); wait >= 0 {
+		t.Fatalf("expected -1 once MaxElapsedTime has passed, got %v", wait)
+	}
+
+	b.Reset()
+	clock.advance(time.Second)
+	if wait := b.NextInterval(1); wait < 0 {
+		t.Fatalf("expected Reset to clear the elapsed-time clock, got %v", wait)
+	}
+}
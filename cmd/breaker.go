@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BreakerState - the three states of the circuit
+type BreakerState int
+
+const (
+	StateClosed BreakerState = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerMetrics - running counters exposed so callers can wire these into
+// logging or Prometheus.
+type BreakerMetrics struct {
+	Successes  uint64
+	Failures   uint64
+	Rejections uint64
+}
+
+// BreakerConfig - tunables for DatabaseBreaker
+type BreakerConfig struct {
+	FailureThreshold uint
+	Backoff          Backoff
+	OnStateChange    func(from, to BreakerState)
+
+	// Logger - defaults to slog.Default() when nil.
+	Logger *slog.Logger
+	// Tracer - defaults to otel.Tracer("databasebreaker") when nil.
+	Tracer trace.Tracer
+}
+
+// breakerCore - the state machine shared by DatabaseBreaker. Kept separate
+// from DatabaseCircuit plumbing so the transition logic can be tested on
+// its own.
+type breakerCore struct {
+	mu sync.Mutex
+
+	state               BreakerState
+	failureThreshold    uint
+	consecutiveFailures uint
+	backoff             Backoff
+	attempt             uint
+	lastBackoff         time.Duration
+	nextRetryAt         time.Time
+	halfOpenInFlight    bool
+	onStateChange       func(from, to BreakerState)
+	metrics             BreakerMetrics
+	// now - overridable in tests so Open->HalfOpen timing can be exercised
+	// with a fake clock instead of real sleeps. Defaults to time.Now.
+	now func() time.Time
+}
+
+func newBreakerCore(cfg BreakerConfig) *breakerCore {
+	backoff := cfg.Backoff
+	if backoff == nil {
+		backoff = NewExponentialBackoff()
+	}
+	return &breakerCore{
+		state:            StateClosed,
+		failureThreshold: cfg.FailureThreshold,
+		backoff:          backoff,
+		onStateChange:    cfg.OnStateChange,
+		now:              time.Now,
+	}
+}
+
+// allow - reports whether a call may proceed, moving Open->HalfOpen once the
+// backoff interval has elapsed and letting exactly one probe through while
+// Half-Open.
+func (c *breakerCore) allow() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch c.state {
+	case StateClosed:
+		return true
+	case StateOpen:
+		if c.now().Before(c.nextRetryAt) {
+			c.metrics.Rejections++
+			return false
+		}
+		c.transition(StateHalfOpen)
+		c.halfOpenInFlight = true
+		return true
+	case StateHalfOpen:
+		if c.halfOpenInFlight {
+			c.metrics.Rejections++
+			return false
+		}
+		c.halfOpenInFlight = true
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *breakerCore) recordSuccess() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.metrics.Successes++
+	c.consecutiveFailures = 0
+	c.halfOpenInFlight = false
+	c.backoff.Reset()
+	c.attempt = 0
+	if c.state != StateClosed {
+		c.transition(StateClosed)
+	}
+}
+
+func (c *breakerCore) recordFailure() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.metrics.Failures++
+	c.halfOpenInFlight = false
+
+	if c.state == StateHalfOpen {
+		c.openWithBackoff()
+		return
+	}
+
+	c.consecutiveFailures++
+	if c.state == StateClosed && c.consecutiveFailures >= c.failureThreshold {
+		c.openWithBackoff()
+	}
+}
+
+// openWithBackoff - transitions to Open and schedules the next retry using
+// the configured Backoff policy.
+func (c *breakerCore) openWithBackoff() {
+	c.attempt++
+	wait := c.backoff.NextInterval(c.attempt)
+	if wait < 0 {
+		c.backoff.Reset()
+		wait = c.backoff.NextInterval(1) // backoff exhausted, fall back to its initial interval
+	}
+	c.lastBackoff = wait
+	c.nextRetryAt = c.now().Add(wait)
+	c.transition(StateOpen)
+}
+
+func (c *breakerCore) transition(to BreakerState) {
+	from := c.state
+	c.state = to
+	if from != to && c.onStateChange != nil {
+		c.onStateChange(from, to)
+	}
+}
+
+func (c *breakerCore) currentState() BreakerState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+func (c *breakerCore) currentMetrics() BreakerMetrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.metrics
+}
+
+func (c *breakerCore) currentAttempt() uint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.attempt
+}
+
+func (c *breakerCore) currentBackoff() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastBackoff
+}
+
+// reset - returns the breaker to a fresh Closed state.
+func (c *breakerCore) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.transition(StateClosed)
+	c.consecutiveFailures = 0
+	c.halfOpenInFlight = false
+	c.attempt = 0
+	c.backoff.Reset()
+}
+
+// DatabaseCircuit -  typed name for the database circuit. Generic over T so
+// the breaker can wrap any DriverFactory-backed constructor, not just
+// NewDataStore.
+type DatabaseCircuit[T any] func(context.Context, string, PoolConfig) (T, error)
+
+// Breaker - wraps a DatabaseCircuit with a Closed/Open/Half-Open state
+// machine. Exported so callers can inspect State()/Metrics() and call
+// Reset() directly.
+type Breaker[T any] struct {
+	core    *breakerCore
+	circuit DatabaseCircuit[T]
+	logger  *slog.Logger
+	tracer  trace.Tracer
+}
+
+// DatabaseBreaker - wraps dbCircuit with a circuit-breaker state machine
+// using cfg's failure threshold, backoff policy and state-change hook.
+func DatabaseBreaker[T any](dbCircuit DatabaseCircuit[T], cfg BreakerConfig) *Breaker[T] {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	tracer := cfg.Tracer
+	if tracer == nil {
+		tracer = otel.Tracer("databasebreaker")
+	}
+
+	return &Breaker[T]{
+		core:    newBreakerCore(cfg),
+		circuit: dbCircuit,
+		logger:  logger,
+		tracer:  tracer,
+	}
+}
+
+// Call - runs the wrapped circuit if the breaker allows it, recording the
+// outcome against the state machine and emitting a span covering the
+// attempt.
+func (b *Breaker[T]) Call(ctx context.Context, dbUrl string, cfg PoolConfig) (T, error) {
+	ctx, span := b.tracer.Start(ctx, "databasebreaker.Call", trace.WithAttributes(
+		attribute.String("db.system", dbSystemFromURL(dbUrl)),
+		attribute.String("db.url", redactDBURL(dbUrl)),
+		attribute.Int64("attempt", int64(b.core.currentAttempt())+1),
+	))
+	defer span.End()
+
+	before := b.core.currentState()
+	allowed := b.core.allow()
+	b.traceStateChange(span, before, b.core.currentState())
+
+	if !allowed {
+		span.SetAttributes(attribute.String("breaker.state", b.core.currentState().String()))
+		b.logger.WarnContext(ctx, "databasebreaker: call rejected", "state", b.core.currentState().String())
+		var zero T
+		return zero, ErrBreakerOpen
+	}
+
+	db, err := b.circuit(ctx, dbUrl, cfg)
+
+	before = b.core.currentState()
+	if err != nil {
+		b.core.recordFailure()
+	} else {
+		b.core.recordSuccess()
+	}
+	after := b.core.currentState()
+	b.traceStateChange(span, before, after)
+
+	span.SetAttributes(
+		attribute.String("breaker.state", after.String()),
+		attribute.Int64("backoff_ms", b.core.currentBackoff().Milliseconds()),
+	)
+
+	if err != nil {
+		wrapped := &ErrConnectFailed{Err: err}
+		span.RecordError(wrapped)
+		b.logger.ErrorContext(ctx, "databasebreaker: call failed", "error", err, "state", after.String())
+		return db, wrapped
+	}
+
+	b.logger.DebugContext(ctx, "databasebreaker: call succeeded", "state", after.String())
+	return db, nil
+}
+
+func (b *Breaker[T]) traceStateChange(span trace.Span, from, to BreakerState) {
+	if from == to {
+		return
+	}
+	span.AddEvent("breaker.state_change", trace.WithAttributes(
+		attribute.String("from", from.String()),
+		attribute.String("to", to.String()),
+	))
+	b.logger.Info("databasebreaker: state change", "from", from.String(), "to", to.String())
+}
+
+// State - the breaker's current state
+func (b *Breaker[T]) State() BreakerState {
+	return b.core.currentState()
+}
+
+// Metrics - a snapshot of the breaker's running counters
+func (b *Breaker[T]) Metrics() BreakerMetrics {
+	return b.core.currentMetrics()
+}
+
+// Reset - forces the breaker back to Closed, clearing failure counts and
+// the backoff policy's elapsed-time clock.
+func (b *Breaker[T]) Reset() {
+	b.core.reset()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakerCore_OpensHalfOpensAndCloses(t *testing.T) {
+	clock := newFakeClock()
+	backoff := &ExponentialBackoff{
+		InitialInterval:     time.Second,
+		MaxInterval:         time.Second * 10,
+		Multiplier:          1,
+		RandomizationFactor: 0,
+		now:                 clock.now,
+	}
+
+	var transitions []string
+	core := newBreakerCore(BreakerConfig{
+		FailureThreshold: 2,
+		Backoff:          backoff,
+		OnStateChange: func(from, to BreakerState) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	})
+	core.now = clock.now
+
+	if !core.allow() {
+		t.Fatal("expected Closed to allow the first call")
+	}
+	core.recordFailure()
+	if core.currentState() != StateClosed {
+		t.Fatalf("after 1/%d failures: got %v, want Closed", core.failureThreshold, core.currentState())
+	}
+
+	if !core.allow() {
+		t.Fatal("expected Closed to allow the second call")
+	}
+	core.recordFailure()
+	if core.currentState() != StateOpen {
+		t.Fatalf("after reaching the failure threshold: got %v, want Open", core.currentState())
+	}
+
+	if core.allow() {
+		t.Fatal("expected Open to reject calls before the backoff interval elapses")
+	}
+	if got := core.currentMetrics().Rejections; got != 1 {
+		t.Fatalf("rejections: got %d, want 1", got)
+	}
+
+	clock.advance(time.Second * 2)
+
+	if !core.allow() {
+		t.Fatal("expected Open to allow a single probe once the backoff interval elapses")
+	}
+	if core.currentState() != StateHalfOpen {
+		t.Fatalf("after backoff elapsed: got %v, want HalfOpen", core.currentState())
+	}
+
+	if core.allow() {
+		t.Fatal("expected HalfOpen to reject a second concurrent probe")
+	}
+
+	core.recordSuccess()
+	if core.currentState() != StateClosed {
+		t.Fatalf("after a successful probe: got %v, want Closed", core.currentState())
+	}
+
+	want := []string{"closed->open", "open->half-open", "half-open->closed"}
+	if len(transitions) != len(want) {
+		t.Fatalf("transitions: got %v, want %v", transitions, want)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Fatalf("transitions[%d]: got %q, want %q", i, transitions[i], want[i])
+		}
+	}
+}
+
+func TestBreakerCore_HalfOpenFailureReopens(t *testing.T) {
+	clock := newFakeClock()
+	backoff := &ExponentialBackoff{
+		InitialInterval:     time.Second,
+		MaxInterval:         time.Second * 10,
+		Multiplier:          1,
+		RandomizationFactor: 0,
+		now:                 clock.now,
+	}
+
+	core := newBreakerCore(BreakerConfig{FailureThreshold: 1, Backoff: backoff})
+	core.now = clock.now
+
+	core.allow()
+	core.recordFailure() // Closed -> Open
+	clock.advance(time.Second * 2)
+	if !core.allow() { // Open -> HalfOpen probe
+		t.Fatal("expected the HalfOpen probe to be allowed")
+	}
+
+	core.recordFailure() // probe fails, should reopen
+	if core.currentState() != StateOpen {
+		t.Fatalf("after a failed probe: got %v, want Open", core.currentState())
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// PoolConfig - exposes the pool-tuning knobs callers typically want to set
+// on top of the base connection URL (mirrors the familiar sql.DB knobs:
+// max-open-conns, max-idle-conns, max-lifetime). Drivers apply whichever of
+// these are relevant to their backend.
+type PoolConfig struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+}
+
+// DataStore - wraps a backend Driver selected by dbUrl's scheme
+type DataStore struct {
+	Driver Driver
+}
+
+// NewDataStore - dispatches to the DriverFactory registered for dbUrl's
+// scheme (postgresql://, cockroachdb://, mysql://, inmem://, ...) and
+// returns a DataStore wrapping the resulting Driver.
+func NewDataStore(ctx context.Context, dbUrl string, cfg PoolConfig) (*DataStore, error) {
+	driver, err := OpenDriver(ctx, dbUrl, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DataStore{
+		Driver: driver,
+	}, nil
+}
+
+// Close - releases the underlying driver's resources
+func (d *DataStore) Close() {
+	d.Driver.Close()
+}
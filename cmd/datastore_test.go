@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestNewDataStore_ConcurrentAcquireUnderSimulatedFailures(t *testing.T) {
+	var calls int64
+	Register("flakytest", func(ctx context.Context, dbUrl string, cfg PoolConfig) (Driver, error) {
+		n := atomic.AddInt64(&calls, 1)
+		if n%3 == 0 {
+			return nil, errors.New("simulated connect failure")
+		}
+		return &InMemDriver{}, nil
+	})
+
+	const workers = 50
+	var (
+		wg                sync.WaitGroup
+		succeeded, failed int64
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ds, err := NewDataStore(context.Background(), "flakytest://db", PoolConfig{MaxConns: 4})
+			if err != nil {
+				atomic.AddInt64(&failed, 1)
+				return
+			}
+			defer ds.Close()
+			atomic.AddInt64(&succeeded, 1)
+		}()
+	}
+	wg.Wait()
+
+	if succeeded+failed != workers {
+		t.Fatalf("got %d successes + %d failures, want %d total", succeeded, failed, workers)
+	}
+	if succeeded == 0 || failed == 0 {
+		t.Fatalf("expected a mix of successes and simulated failures from concurrent acquisitions, got %d/%d", succeeded, failed)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Tx - a minimal cross-backend transaction handle. Every Driver's Begin
+// returns one of these so callers can Exec/Commit/Rollback without caring
+// which backend they're talking to.
+type Tx interface {
+	Exec(ctx context.Context, sql string, args ...any) (any, error)
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+// RowScanner - a single-row result, returned by Driver.QueryRow.
+type RowScanner interface {
+	Scan(dest ...any) error
+}
+
+// ErrNoRows - the backend-agnostic "no rows" sentinel; every Driver's
+// QueryRow translates its own no-rows error into this one so callers don't
+// need to import a specific backend package to check for it.
+var ErrNoRows = errors.New("databasebreaker: no rows")
+
+// Driver - backend-agnostic handle obtained from a DriverFactory. Postgres,
+// CockroachDB, MySQL and the in-memory test backend all implement this same
+// surface so DatabaseBreaker can wrap any of them.
+type Driver interface {
+	Begin(ctx context.Context) (Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (any, error)
+	Query(ctx context.Context, sql string, args ...any) (any, error)
+	QueryRow(ctx context.Context, sql string, args ...any) RowScanner
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// DriverFactory - builds a Driver from a connection URL and pool config.
+type DriverFactory func(ctx context.Context, dbUrl string, cfg PoolConfig) (Driver, error)
+
+var (
+	driverRegistryMu sync.RWMutex
+	driverRegistry   = map[string]DriverFactory{}
+)
+
+// Register - registers a DriverFactory under a URL scheme, e.g.
+// "postgresql" or "mysql". Built-in drivers call this from their own
+// init(); callers can do the same to plug in additional backends.
+func Register(scheme string, factory DriverFactory) {
+	driverRegistryMu.Lock()
+	defer driverRegistryMu.Unlock()
+	driverRegistry[scheme] = factory
+}
+
+func lookupDriver(scheme string) (DriverFactory, error) {
+	driverRegistryMu.RLock()
+	defer driverRegistryMu.RUnlock()
+	factory, ok := driverRegistry[scheme]
+	if !ok {
+		return nil, fmt.Errorf("databasebreaker: no driver registered for scheme %q", scheme)
+	}
+	return factory, nil
+}
+
+// OpenDriver - dispatches to the DriverFactory registered for dbUrl's
+// scheme (postgresql://, cockroachdb://, mysql://, inmem://, ...).
+func OpenDriver(ctx context.Context, dbUrl string, cfg PoolConfig) (Driver, error) {
+	u, err := url.Parse(dbUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	factory, err := lookupDriver(strings.ToLower(u.Scheme))
+	if err != nil {
+		return nil, err
+	}
+	return factory(ctx, dbUrl, cfg)
+}
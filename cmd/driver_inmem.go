@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+func init() {
+	Register("inmem", newInMemDriver)
+}
+
+// InMemDriver - a Driver with no real backend, useful for exercising
+// DatabaseBreaker deterministically without a live database. FailPing and
+// FailExec let tests simulate outages on demand.
+type InMemDriver struct {
+	mu       sync.Mutex
+	FailPing bool
+	FailExec bool
+	Executed []string
+	closed   bool
+}
+
+func newInMemDriver(ctx context.Context, dbUrl string, cfg PoolConfig) (Driver, error) {
+	return &InMemDriver{}, nil
+}
+
+func (d *InMemDriver) Begin(ctx context.Context) (Tx, error) {
+	return &inMemTx{driver: d}, nil
+}
+
+func (d *InMemDriver) Exec(ctx context.Context, sql string, args ...any) (any, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.FailExec {
+		return nil, errors.New("inmem: simulated exec failure")
+	}
+	d.Executed = append(d.Executed, sql)
+	return nil, nil
+}
+
+func (d *InMemDriver) Query(ctx context.Context, sql string, args ...any) (any, error) {
+	return nil, nil
+}
+
+// QueryRow - InMemDriver keeps no real rows, so it always reports
+// ErrNoRows; it's a breaker test-double, not a storage engine.
+func (d *InMemDriver) QueryRow(ctx context.Context, sql string, args ...any) RowScanner {
+	return inMemRow{}
+}
+
+type inMemRow struct{}
+
+func (inMemRow) Scan(dest ...any) error {
+	return ErrNoRows
+}
+
+func (d *InMemDriver) Ping(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.FailPing {
+		return errors.New("inmem: simulated ping failure")
+	}
+	return nil
+}
+
+func (d *InMemDriver) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+}
+
+// inMemTx - Tx implementation over InMemDriver; Exec delegates to the
+// driver so InMemDriver.Executed captures statements run in-transaction too.
+type inMemTx struct {
+	driver *InMemDriver
+}
+
+func (t *inMemTx) Exec(ctx context.Context, sql string, args ...any) (any, error) {
+	return t.driver.Exec(ctx, sql, args...)
+}
+
+func (t *inMemTx) Commit(ctx context.Context) error {
+	return nil
+}
+
+func (t *inMemTx) Rollback(ctx context.Context) error {
+	return nil
+}
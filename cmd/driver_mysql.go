@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+func init() {
+	Register("mysql", newMySQLDriver)
+}
+
+// sqlDriver - Driver implementation backing any database/sql driver; used
+// here for MySQL via go-sql-driver/mysql.
+type sqlDriver struct {
+	db *sql.DB
+}
+
+func newMySQLDriver(ctx context.Context, dbUrl string, cfg PoolConfig) (Driver, error) {
+	dsn := strings.TrimPrefix(dbUrl, "mysql://")
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.MaxConns > 0 {
+		db.SetMaxOpenConns(int(cfg.MaxConns))
+	}
+	if cfg.MinConns > 0 {
+		db.SetMaxIdleConns(int(cfg.MinConns))
+	}
+	if cfg.MaxConnLifetime > 0 {
+		db.SetConnMaxLifetime(cfg.MaxConnLifetime)
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		db.SetConnMaxIdleTime(cfg.MaxConnIdleTime)
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &sqlDriver{db: db}, nil
+}
+
+func (d *sqlDriver) Begin(ctx context.Context) (Tx, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{tx: tx}, nil
+}
+
+func (d *sqlDriver) Exec(ctx context.Context, sql string, args ...any) (any, error) {
+	return d.db.ExecContext(ctx, sql, args...)
+}
+
+func (d *sqlDriver) Query(ctx context.Context, sql string, args ...any) (any, error) {
+	return d.db.QueryContext(ctx, sql, args...)
+}
+
+func (d *sqlDriver) QueryRow(ctx context.Context, sql string, args ...any) RowScanner {
+	return sqlRowScanner{row: d.db.QueryRowContext(ctx, sql, args...)}
+}
+
+// sqlRowScanner - RowScanner wrapping a *sql.Row, translating
+// sql.ErrNoRows into the shared ErrNoRows.
+type sqlRowScanner struct {
+	row *sql.Row
+}
+
+func (r sqlRowScanner) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNoRows
+	}
+	return err
+}
+
+func (d *sqlDriver) Ping(ctx context.Context) error {
+	return d.db.PingContext(ctx)
+}
+
+func (d *sqlDriver) Close() {
+	d.db.Close()
+}
+
+// sqlTx - Tx implementation wrapping a database/sql transaction.
+type sqlTx struct {
+	tx *sql.Tx
+}
+
+func (t *sqlTx) Exec(ctx context.Context, sql string, args ...any) (any, error) {
+	return t.tx.ExecContext(ctx, sql, args...)
+}
+
+func (t *sqlTx) Commit(ctx context.Context) error {
+	return t.tx.Commit()
+}
+
+func (t *sqlTx) Rollback(ctx context.Context) error {
+	return t.tx.Rollback()
+}
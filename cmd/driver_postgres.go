@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func init() {
+	Register("postgresql", newPgxDriver)
+	Register("postgres", newPgxDriver)
+	Register("cockroachdb", newPgxDriver)
+}
+
+// pgxDriver - Driver implementation backing both Postgres and CockroachDB,
+// since CockroachDB speaks the Postgres wire protocol.
+type pgxDriver struct {
+	pool *pgxpool.Pool
+}
+
+func newPgxDriver(ctx context.Context, dbUrl string, cfg PoolConfig) (Driver, error) {
+	// pgx only parses postgres:// and postgresql:// URLs, so a cockroachdb://
+	// URL is handed to it under the postgresql scheme.
+	if rest, ok := strings.CutPrefix(dbUrl, "cockroachdb://"); ok {
+		dbUrl = "postgresql://" + rest
+	}
+
+	config, err := pgxpool.ParseConfig(dbUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.MaxConns > 0 {
+		config.MaxConns = cfg.MaxConns
+	}
+	if cfg.MinConns > 0 {
+		config.MinConns = cfg.MinConns
+	}
+	if cfg.MaxConnLifetime > 0 {
+		config.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		config.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		config.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pgxDriver{pool: pool}, nil
+}
+
+func (d *pgxDriver) Begin(ctx context.Context) (Tx, error) {
+	conn, err := d.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		conn.Release()
+		return nil, err
+	}
+
+	return &pgxTx{tx: tx, conn: conn}, nil
+}
+
+func (d *pgxDriver) Exec(ctx context.Context, sql string, args ...any) (any, error) {
+	return d.pool.Exec(ctx, sql, args...)
+}
+
+func (d *pgxDriver) Query(ctx context.Context, sql string, args ...any) (any, error) {
+	return d.pool.Query(ctx, sql, args...)
+}
+
+func (d *pgxDriver) QueryRow(ctx context.Context, sql string, args ...any) RowScanner {
+	return pgxRow{row: d.pool.QueryRow(ctx, sql, args...)}
+}
+
+// pgxRow - RowScanner wrapping a pgx.Row, translating pgx.ErrNoRows into
+// the shared ErrNoRows.
+type pgxRow struct {
+	row pgx.Row
+}
+
+func (r pgxRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNoRows
+	}
+	return err
+}
+
+func (d *pgxDriver) Ping(ctx context.Context) error {
+	return d.pool.Ping(ctx)
+}
+
+func (d *pgxDriver) Close() {
+	d.pool.Close()
+}
+
+// AcquireSession - pins a pooled connection so a session-scoped advisory
+// lock taken on it survives across transactions, as pg_try_advisory_lock
+// requires.
+func (d *pgxDriver) AcquireSession(ctx context.Context) (LockSession, error) {
+	conn, err := d.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pgxLockSession{conn: conn}, nil
+}
+
+// pgxLockSession - LockSession implementation over a pinned pgxpool.Conn.
+type pgxLockSession struct {
+	conn *pgxpool.Conn
+}
+
+func (s *pgxLockSession) TryLock(ctx context.Context, key int64) (bool, error) {
+	var locked bool
+	err := s.conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked)
+	return locked, err
+}
+
+func (s *pgxLockSession) Unlock(ctx context.Context, key int64) error {
+	_, err := s.conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", key)
+	return err
+}
+
+func (s *pgxLockSession) Release() {
+	s.conn.Release()
+}
+
+// pgxTx - Tx implementation wrapping a pgx.Tx bound to a pooled connection;
+// the connection is released back to the pool on Commit/Rollback.
+type pgxTx struct {
+	tx   pgx.Tx
+	conn *pgxpool.Conn
+}
+
+func (t *pgxTx) Exec(ctx context.Context, sql string, args ...any) (any, error) {
+	return t.tx.Exec(ctx, sql, args...)
+}
+
+func (t *pgxTx) Commit(ctx context.Context) error {
+	defer t.conn.Release()
+	return t.tx.Commit(ctx)
+}
+
+func (t *pgxTx) Rollback(ctx context.Context) error {
+	defer t.conn.Release()
+	return t.tx.Rollback(ctx)
+}
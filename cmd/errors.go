@@ -0,0 +1,26 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrBreakerOpen - the circuit is Open (or a Half-Open probe is already in
+// flight) and the call was rejected without the wrapped circuit running.
+var ErrBreakerOpen = errors.New("databasebreaker: breaker open")
+
+// ErrConnectFailed - wraps a failed attempt from the wrapped circuit, so
+// callers can distinguish "the breaker rejected the call" (ErrBreakerOpen)
+// from "the call ran and failed to connect", and still reach the
+// underlying driver error via errors.Is/errors.As.
+type ErrConnectFailed struct {
+	Err error
+}
+
+func (e *ErrConnectFailed) Error() string {
+	return fmt.Sprintf("databasebreaker: connect failed: %s", e.Err)
+}
+
+func (e *ErrConnectFailed) Unwrap() error {
+	return e.Err
+}
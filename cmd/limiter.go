@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrLimitExceeded - returned when a Limiter's connection or rate limit
+// trips. Distinct from Breaker's ErrBreakerOpen so callers can tell
+// "caller is misbehaving" apart from "the database is down".
+var ErrLimitExceeded = errors.New("databasebreaker: limit exceeded")
+
+type limiterKeyCtx struct{}
+
+// WithLimiterKey - attaches a per-caller key (client IP, tenant ID, ...) to
+// ctx so a Limiter wrapping the call made with it applies that key's
+// per-key limits.
+func WithLimiterKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, limiterKeyCtx{}, key)
+}
+
+func limiterKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(limiterKeyCtx{}).(string)
+	return key
+}
+
+// LimiterConfig - the global and per-key limits enforced by a Limiter.
+// Rate limits are tokens-per-second with the given burst capacity; zero
+// disables that particular check.
+type LimiterConfig struct {
+	MaxInFlight       int
+	PerKeyMaxInFlight int
+	RateLimit         float64
+	RateBurst         int
+	PerKeyRateLimit   float64
+	PerKeyRateBurst   int
+}
+
+// tokenBucket - a simple token-bucket rate limiter refilled lazily on
+// allow() so it needs no background goroutine.
+type tokenBucket struct {
+	mu       sync.Mutex
+	tokens   float64
+	max      float64
+	rate     float64
+	lastFill time.Time
+	// now - overridable in tests so refill timing can be exercised with a
+	// fake clock instead of real sleeps. Defaults to time.Now.
+	now func() time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		tokens:   float64(burst),
+		max:      float64(burst),
+		rate:     rate,
+		lastFill: time.Now(),
+		now:      time.Now,
+	}
+}
+
+func (b *tokenBucket) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens += now.Sub(b.lastFill).Seconds() * b.rate
+	if b.tokens > b.max {
+		b.tokens = b.max
+	}
+	b.lastFill = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Limiter - wraps a DatabaseCircuit (typically a Breaker's Call, or vice
+// versa) enforcing a maximum number of in-flight acquisitions and a
+// token-bucket rate on new attempts, both globally and per caller-supplied
+// key.
+type Limiter[T any] struct {
+	cfg     LimiterConfig
+	circuit DatabaseCircuit[T]
+
+	mu             sync.Mutex
+	inFlight       int
+	perKeyInFlight map[string]int
+	globalBucket   *tokenBucket
+	perKeyBuckets  map[string]*tokenBucket
+}
+
+// NewLimiter - wraps circuit with cfg's global and per-key limits.
+func NewLimiter[T any](circuit DatabaseCircuit[T], cfg LimiterConfig) *Limiter[T] {
+	l := &Limiter[T]{
+		cfg:            cfg,
+		circuit:        circuit,
+		perKeyInFlight: map[string]int{},
+		perKeyBuckets:  map[string]*tokenBucket{},
+	}
+	if cfg.RateLimit > 0 {
+		l.globalBucket = newTokenBucket(cfg.RateLimit, cfg.RateBurst)
+	}
+	return l
+}
+
+func (l *Limiter[T]) bucketFor(key string) *tokenBucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.perKeyBuckets[key]
+	if !ok {
+		b = newTokenBucket(l.cfg.PerKeyRateLimit, l.cfg.PerKeyRateBurst)
+		l.perKeyBuckets[key] = b
+	}
+	return b
+}
+
+// Call - runs circuit if neither the global nor the per-key (from ctx, see
+// WithLimiterKey) in-flight and rate limits are tripped.
+func (l *Limiter[T]) Call(ctx context.Context, dbUrl string, cfg PoolConfig) (T, error) {
+	var zero T
+	key := limiterKeyFromContext(ctx)
+
+	if l.globalBucket != nil && !l.globalBucket.allow() {
+		return zero, ErrLimitExceeded
+	}
+	if key != "" && l.cfg.PerKeyRateLimit > 0 && !l.bucketFor(key).allow() {
+		return zero, ErrLimitExceeded
+	}
+
+	l.mu.Lock()
+	if l.cfg.MaxInFlight > 0 && l.inFlight >= l.cfg.MaxInFlight {
+		l.mu.Unlock()
+		return zero, ErrLimitExceeded
+	}
+	if key != "" && l.cfg.PerKeyMaxInFlight > 0 && l.perKeyInFlight[key] >= l.cfg.PerKeyMaxInFlight {
+		l.mu.Unlock()
+		return zero, ErrLimitExceeded
+	}
+	l.inFlight++
+	if key != "" {
+		l.perKeyInFlight[key]++
+	}
+	l.mu.Unlock()
+
+	defer func() {
+		l.mu.Lock()
+		l.inFlight--
+		if key != "" {
+			l.perKeyInFlight[key]--
+		}
+		l.mu.Unlock()
+	}()
+
+	return l.circuit(ctx, dbUrl, cfg)
+}
+
+// ActiveConns - a snapshot of in-flight acquisitions, globally and per key,
+// suitable for exposing as gauges.
+func (l *Limiter[T]) ActiveConns() (global int, perKey map[string]int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	perKey = make(map[string]int, len(l.perKeyInFlight))
+	for k, v := range l.perKeyInFlight {
+		perKey[k] = v
+	}
+	return l.inFlight, perKey
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func blockingCircuit(release <-chan struct{}) DatabaseCircuit[int] {
+	return func(ctx context.Context, dbUrl string, cfg PoolConfig) (int, error) {
+		<-release
+		return 1, nil
+	}
+}
+
+func TestLimiter_MaxInFlight(t *testing.T) {
+	release := make(chan struct{})
+	limiter := NewLimiter(blockingCircuit(release), LimiterConfig{MaxInFlight: 2})
+
+	const workers = 5
+	var (
+		wg                sync.WaitGroup
+		mu                sync.Mutex
+		accepted, limited int
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := limiter.Call(context.Background(), "inmem://db", PoolConfig{})
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				accepted++
+			case errors.Is(err, ErrLimitExceeded):
+				limited++
+			default:
+				t.Errorf("Call: unexpected error: %v", err)
+			}
+		}()
+	}
+
+	// Give every caller time to either claim one of the MaxInFlight slots or
+	// be rejected before checking the gauge.
+	time.Sleep(time.Millisecond * 20)
+	if global, _ := limiter.ActiveConns(); global != 2 {
+		t.Fatalf("ActiveConns global: got %d, want 2", global)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if accepted != 2 {
+		t.Fatalf("accepted: got %d, want 2", accepted)
+	}
+	if limited != workers-2 {
+		t.Fatalf("limited: got %d, want %d", limited, workers-2)
+	}
+	if global, perKey := limiter.ActiveConns(); global != 0 || len(perKey) != 0 {
+		t.Fatalf("ActiveConns after completion: got (%d, %v), want (0, empty)", global, perKey)
+	}
+}
+
+func TestLimiter_PerKeyMaxInFlight(t *testing.T) {
+	release := make(chan struct{})
+	limiter := NewLimiter(blockingCircuit(release), LimiterConfig{PerKeyMaxInFlight: 1})
+
+	ctxA := WithLimiterKey(context.Background(), "tenant-a")
+	ctxB := WithLimiterKey(context.Background(), "tenant-b")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+
+	call := func(ctx context.Context) {
+		defer wg.Done()
+		_, err := limiter.Call(ctx, "inmem://db", PoolConfig{})
+		errs <- err
+	}
+
+	wg.Add(3)
+	go call(ctxA)
+	go call(ctxA)
+	time.Sleep(time.Millisecond * 20)
+	go call(ctxB)
+	time.Sleep(time.Millisecond * 20)
+
+	if _, perKey := limiter.ActiveConns(); perKey["tenant-a"] != 1 || perKey["tenant-b"] != 1 {
+		t.Fatalf("ActiveConns per key: got %v, want tenant-a:1 tenant-b:1", perKey)
+	}
+
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	var limited int
+	for err := range errs {
+		if errors.Is(err, ErrLimitExceeded) {
+			limited++
+		} else if err != nil {
+			t.Errorf("Call: unexpected error: %v", err)
+		}
+	}
+	if limited != 1 {
+		t.Fatalf("limited: got %d, want 1 (the second tenant-a caller)", limited)
+	}
+}
+
+func TestTokenBucket_RefillAndBurst(t *testing.T) {
+	clock := newFakeClock()
+	b := newTokenBucket(1, 2) // 1 token/sec, burst of 2
+	b.now = clock.now
+	b.lastFill = clock.now()
+
+	if !b.allow() {
+		t.Fatal("expected the first call to consume a burst token")
+	}
+	if !b.allow() {
+		t.Fatal("expected the second call to consume the last burst token")
+	}
+	if b.allow() {
+		t.Fatal("expected the bucket to be empty after the burst is spent")
+	}
+
+	clock.advance(time.Millisecond * 500)
+	if b.allow() {
+		t.Fatal("expected half a refill interval to still be insufficient for a token")
+	}
+
+	clock.advance(time.Millisecond * 500)
+	if !b.allow() {
+		t.Fatal("expected a full second to have refilled exactly one token")
+	}
+
+	clock.advance(time.Second * 10)
+	if !b.allow() {
+		t.Fatal("expected a long idle period to refill at least one token")
+	}
+	if b.tokens > b.max {
+		t.Fatalf("tokens: got %v, want capped at max %v", b.tokens, b.max)
+	}
+}
+
+func TestLimiter_RateLimitExceeded(t *testing.T) {
+	clock := newFakeClock()
+	limiter := NewLimiter(func(ctx context.Context, dbUrl string, cfg PoolConfig) (int, error) {
+		return 1, nil
+	}, LimiterConfig{RateLimit: 1, RateBurst: 1})
+	limiter.globalBucket.now = clock.now
+	limiter.globalBucket.lastFill = clock.now()
+
+	if _, err := limiter.Call(context.Background(), "inmem://db", PoolConfig{}); err != nil {
+		t.Fatalf("first Call: %v", err)
+	}
+	if _, err := limiter.Call(context.Background(), "inmem://db", PoolConfig{}); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("second Call: got %v, want ErrLimitExceeded", err)
+	}
+
+	clock.advance(time.Second)
+	if _, err := limiter.Call(context.Background(), "inmem://db", PoolConfig{}); err != nil {
+		t.Fatalf("Call after refill: %v", err)
+	}
+}
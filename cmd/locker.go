@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+)
+
+// ErrLockingNotSupported - returned when the DataStore's underlying Driver
+// doesn't implement AdvisoryLocker (only the Postgres/CockroachDB driver
+// does today).
+var ErrLockingNotSupported = errors.New("databasebreaker: driver does not support advisory locks")
+
+// ErrLockHeld - returned by WithLock/AcquireLock when the key is already
+// locked by another session and no retry policy was given (or it ran out).
+var ErrLockHeld = errors.New("databasebreaker: lock already held")
+
+// LockSession - a pooled connection pinned for the lifetime of a
+// session-scoped advisory lock, so the lock survives across transactions
+// run against it.
+type LockSession interface {
+	TryLock(ctx context.Context, key int64) (bool, error)
+	Unlock(ctx context.Context, key int64) error
+	Release()
+}
+
+// AdvisoryLocker - implemented by drivers that support session-scoped
+// advisory locks (pg_try_advisory_lock/pg_advisory_unlock on Postgres and
+// CockroachDB).
+type AdvisoryLocker interface {
+	AcquireSession(ctx context.Context) (LockSession, error)
+}
+
+// Locker - distributed advisory-lock helper bound to a DataStore, used to
+// coordinate singleton startup work (schema setup, migrations) across
+// nodes.
+type Locker struct {
+	ds *DataStore
+}
+
+// Locker - returns a Locker for d, or ErrLockingNotSupported if d's driver
+// doesn't implement AdvisoryLocker.
+func (d *DataStore) Locker() (*Locker, error) {
+	if _, ok := d.Driver.(AdvisoryLocker); !ok {
+		return nil, ErrLockingNotSupported
+	}
+	return &Locker{ds: d}, nil
+}
+
+// lockKey - normalizes a caller-supplied int64 or string into the int64 key
+// pg_try_advisory_lock expects, hashing strings with FNV-1a.
+func lockKey(key any) (int64, error) {
+	switch k := key.(type) {
+	case int64:
+		return k, nil
+	case string:
+		h := fnv.New64a()
+		h.Write([]byte(k))
+		return int64(h.Sum64()), nil
+	default:
+		return 0, fmt.Errorf("databasebreaker: unsupported lock key type %T", key)
+	}
+}
+
+// WithLock - acquires the advisory lock for key, runs fn while it's held,
+// and always releases it (and the pinned connection) before returning.
+func (l *Locker) WithLock(ctx context.Context, key any, fn func(ctx context.Context) error) error {
+	k, err := lockKey(key)
+	if err != nil {
+		return err
+	}
+
+	session, err := l.ds.Driver.(AdvisoryLocker).AcquireSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer session.Release()
+
+	locked, err := session.TryLock(ctx, k)
+	if err != nil {
+		return err
+	}
+	if !locked {
+		return ErrLockHeld
+	}
+	defer session.Unlock(ctx, k)
+
+	return fn(ctx)
+}
+
+// HeldLock - a lock acquired via AcquireLock. Release unlocks it and
+// returns the pinned connection to the pool.
+type HeldLock struct {
+	session LockSession
+	key     int64
+}
+
+// Release - unlocks the advisory lock and releases the pinned connection.
+func (h *HeldLock) Release(ctx context.Context) error {
+	defer h.session.Release()
+	return h.session.Unlock(ctx, h.key)
+}
+
+// AcquireLock - blocks until the advisory lock for key is acquired,
+// retrying with retry's backoff policy between attempts. Returns
+// ErrLockHeld once retry reports its backoff exhausted, or ctx's error if
+// ctx is cancelled first.
+func (l *Locker) AcquireLock(ctx context.Context, key any, retry Backoff) (*HeldLock, error) {
+	k, err := lockKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	locker, ok := l.ds.Driver.(AdvisoryLocker)
+	if !ok {
+		return nil, ErrLockingNotSupported
+	}
+
+	session, err := locker.AcquireSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var attempt uint
+	for {
+		locked, err := session.TryLock(ctx, k)
+		if err != nil {
+			session.Release()
+			return nil, err
+		}
+		if locked {
+			return &HeldLock{session: session, key: k}, nil
+		}
+
+		attempt++
+		wait := retry.NextInterval(attempt)
+		if wait < 0 {
+			session.Release()
+			return nil, ErrLockHeld
+		}
+
+		select {
+		case <-ctx.Done():
+			session.Release()
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLockDriver - an in-process stand-in for a Postgres/CockroachDB driver
+// implementing AdvisoryLocker, used to race goroutines for the same key the
+// way two separate processes racing pg_try_advisory_lock would.
+type fakeLockDriver struct {
+	mu     sync.Mutex
+	locked map[int64]bool
+}
+
+func newFakeLockDriver() *fakeLockDriver {
+	return &fakeLockDriver{locked: map[int64]bool{}}
+}
+
+func (d *fakeLockDriver) Begin(ctx context.Context) (Tx, error) {
+	return nil, errors.New("not implemented")
+}
+func (d *fakeLockDriver) Exec(ctx context.Context, sql string, args ...any) (any, error) {
+	return nil, nil
+}
+func (d *fakeLockDriver) Query(ctx context.Context, sql string, args ...any) (any, error) {
+	return nil, nil
+}
+func (d *fakeLockDriver) QueryRow(ctx context.Context, sql string, args ...any) RowScanner {
+	return inMemRow{}
+}
+func (d *fakeLockDriver) Ping(ctx context.Context) error { return nil }
+func (d *fakeLockDriver) Close()                         {}
+
+func (d *fakeLockDriver) AcquireSession(ctx context.Context) (LockSession, error) {
+	return &fakeLockSession{driver: d}, nil
+}
+
+type fakeLockSession struct {
+	driver *fakeLockDriver
+}
+
+func (s *fakeLockSession) TryLock(ctx context.Context, key int64) (bool, error) {
+	s.driver.mu.Lock()
+	defer s.driver.mu.Unlock()
+	if s.driver.locked[key] {
+		return false, nil
+	}
+	s.driver.locked[key] = true
+	return true, nil
+}
+
+func (s *fakeLockSession) Unlock(ctx context.Context, key int64) error {
+	s.driver.mu.Lock()
+	defer s.driver.mu.Unlock()
+	delete(s.driver.locked, key)
+	return nil
+}
+
+func (s *fakeLockSession) Release() {}
+
+func TestLocker_WithLock_MutualExclusion(t *testing.T) {
+	ds := &DataStore{Driver: newFakeLockDriver()}
+	locker, err := ds.Locker()
+	if err != nil {
+		t.Fatalf("Locker(): %v", err)
+	}
+
+	const workers = 8
+	var (
+		mu        sync.Mutex
+		active    int
+		maxActive int
+		successes int
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := locker.WithLock(context.Background(), "race-key", func(ctx context.Context) error {
+				mu.Lock()
+				active++
+				if active > maxActive {
+					maxActive = active
+				}
+				mu.Unlock()
+
+				time.Sleep(time.Millisecond)
+
+				mu.Lock()
+				active--
+				successes++
+				mu.Unlock()
+				return nil
+			})
+			if err != nil && !errors.Is(err, ErrLockHeld) {
+				t.Errorf("WithLock: unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxActive > 1 {
+		t.Fatalf("observed %d concurrent lock holders racing the same key, want at most 1", maxActive)
+	}
+	if successes == 0 {
+		t.Fatal("expected at least one goroutine to acquire the lock")
+	}
+}
+
+func TestLocker_AcquireLock_BlocksUntilReleased(t *testing.T) {
+	driver := newFakeLockDriver()
+	ds := &DataStore{Driver: driver}
+	locker, err := ds.Locker()
+	if err != nil {
+		t.Fatalf("Locker(): %v", err)
+	}
+	retry := &ExponentialBackoff{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond * 5, Multiplier: 1}
+
+	held, err := locker.AcquireLock(context.Background(), "blocking-key", retry)
+	if err != nil {
+		t.Fatalf("first AcquireLock: %v", err)
+	}
+
+	unblocked := make(chan struct{})
+	go func() {
+		defer close(unblocked)
+		second, err := locker.AcquireLock(context.Background(), "blocking-key", retry)
+		if err != nil {
+			t.Errorf("second AcquireLock: %v", err)
+			return
+		}
+		second.Release(context.Background())
+	}()
+
+	time.Sleep(time.Millisecond * 20)
+	select {
+	case <-unblocked:
+		t.Fatal("second AcquireLock returned before the first lock was released")
+	default:
+	}
+
+	if err := held.Release(context.Background()); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+
+	select {
+	case <-unblocked:
+	case <-time.After(time.Second):
+		t.Fatal("second AcquireLock did not unblock after the first lock's Release")
+	}
+}
@@ -3,88 +3,57 @@ package main
 import (
 	"context"
 	"errors"
-	"fmt"
-	"github.com/jackc/pgx/v5"
-	"log"
-	"sync"
+	"log/slog"
+	"os"
 	"time"
 )
 
 var (
 	database_url = "postgresql://root@127.0.0.1:26257/defaultdb?sslmode=disable"
-)
-
-type DataStore struct {
-	Conn *pgx.Conn
-}
-
-// NewDataStore - creates a new database connection object
-func NewDataStore(ctx context.Context, dbUrl string) (*DataStore, error) {
-	if config, err := pgx.ParseConfig(dbUrl); err != nil {
-		return nil, err
-	} else if conn, err := pgx.ConnectConfig(ctx, config); err != nil {
-		return nil, err
-	} else {
-		return &DataStore{
-			Conn: conn,
-		}, nil
+	poolConfig   = PoolConfig{
+		MaxConns:          10,
+		MinConns:          1,
+		MaxConnLifetime:   time.Hour,
+		MaxConnIdleTime:   time.Minute * 30,
+		HealthCheckPeriod: time.Minute,
 	}
-
-}
-
-// DatabaseCircuit -  typed name for the database circuit
-type DatabaseCircuit func(context.Context, string) (*DataStore, error)
-
-// DatabaseBreaker - Creates a limited amount of retries on the database connection
-func DatabaseBreaker(dbCircuit DatabaseCircuit, failureThreshold uint) DatabaseCircuit {
-	var consecutiveFailures int = 0
-	var lastAttempt = time.Now()
-	var mu sync.RWMutex
-
-	return func(ctx context.Context, dbUrl string) (*DataStore, error) {
-		mu.RLock() // establish a read-lock
-		d := consecutiveFailures - int(failureThreshold)
-
-		if d >= 0 {
-			shouldRetryAt := lastAttempt.Add(time.Second * 2 << d) // Note: 'Effectively' doubles the wait time for the next call.
-			if !time.Now().After(shouldRetryAt) {
-				mu.RUnlock()
-				return nil, errors.New("service unavailable")
-			}
-		}
-
-		mu.RUnlock()                     // release read-lock
-		db, err := dbCircuit(ctx, dbUrl) // issue vanilla(non-wrapped) function call
-
-		mu.Lock() // lock shared resources
-		defer mu.Unlock()
-		lastAttempt = time.Now() // record the time of db access attempt
-		if err != nil {
-			consecutiveFailures++ // count failure
-			return db, err        // return values non-wrapped function would like a normal call
-		}
-		consecutiveFailures = 0 // reset failure count
-		return db, nil          // return resources upon success
-	}
-}
+)
 
 func main() {
-	NewDataStoreBreaker := DatabaseBreaker(NewDataStore, 4) // give 4 attempts
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
-	roachdb, err := NewDataStoreBreaker(context.Background(), database_url)
+	breaker := DatabaseBreaker(NewDataStore, BreakerConfig{
+		FailureThreshold: 4, // give 4 attempts
+		Backoff:          NewExponentialBackoff(),
+		Logger:           logger,
+		OnStateChange: func(from, to BreakerState) {
+			logger.Info("breaker state change", "from", from, "to", to)
+		},
+	})
+	// Cap in-flight connection attempts so one misbehaving caller can't
+	// exhaust the breaker's failure budget for everyone else.
+	limiter := NewLimiter(breaker.Call, LimiterConfig{
+		MaxInFlight:       10,
+		PerKeyMaxInFlight: 3,
+		RateLimit:         5,
+		RateBurst:         10,
+	})
+
+	roachdb, err := limiter.Call(context.Background(), database_url, poolConfig)
 	if err != nil {
 		wait := make(chan struct{})
 		dbStream := make(chan *DataStore)
-		fmt.Println("Connecting....")
+		logger.Info("connecting...")
 		go func(db *DataStore) {
-			routineDeadline := time.Now().Add(time.Second * 32) // enough time for 8 attempts within 32 seconds. As an example, it will response with 'service unavailable' on the last attempt
+			routineDeadline := time.Now().Add(time.Second * 32) // enough time for 8 attempts within 32 seconds. As an example, the 4th failure opens the breaker; the default backoff stays under the 4-second tick, so each later attempt runs as a Half-Open probe and fails with ErrConnectFailed
 			for range time.Tick(time.Second * 4) {
 				if time.Now().After(routineDeadline) {
-					log.Fatalf("Database connectivity could not be acquired")
+					logger.Error("database connectivity could not be acquired")
+					os.Exit(1)
 				}
-				log.Println("Trying to connect...")
-				if db, err = NewDataStoreBreaker(context.Background(), database_url); err != nil {
-					fmt.Printf("%s \n", err.Error())
+				logger.Info("trying to connect...")
+				if db, err = limiter.Call(context.Background(), database_url, poolConfig); err != nil {
+					logger.Warn("connect attempt failed", "error", err)
 				} else {
 					dbStream <- db
 					close(wait)
@@ -95,16 +64,33 @@ func main() {
 		roachdb = <-dbStream // send the database connection by channel
 		<-wait
 	}
-	log.Println("database connected")
+	defer roachdb.Close()
+	logger.Info("database connected")
 	ctx := context.Background()
-	if tx, err := roachdb.Conn.Begin(ctx); err != nil {
-		log.Println("Could not begin database transaction ", err)
-	} else if _, err = tx.Exec(ctx, "CREATE DATABASE subjectives"); err != nil {
-		log.Println("Error creating database")
-	} else {
-		err := tx.Commit(ctx)
+
+	locker, err := roachdb.Locker()
+	if err != nil {
+		logger.Error("could not build locker", "error", err)
+		os.Exit(1)
+	}
+
+	// Only one node should run this schema setup, so it's gated behind an
+	// advisory lock keyed by a string naming this setup step.
+	err = locker.WithLock(ctx, "subjectives-schema-setup", func(ctx context.Context) error {
+		tx, err := roachdb.Driver.Begin(ctx)
 		if err != nil {
-			log.Fatalf("Could not commit creation of database %s \n", err.Error())
+			logger.Error("could not begin database transaction", "error", err)
+			return err
+		}
+		if _, err = tx.Exec(ctx, "CREATE DATABASE subjectives"); err != nil {
+			logger.Error("error creating database", "error", err)
+			tx.Rollback(ctx)
+			return err
 		}
+		return tx.Commit(ctx)
+	})
+	if err != nil && !errors.Is(err, ErrLockHeld) {
+		logger.Error("could not commit creation of database", "error", err)
+		os.Exit(1)
 	}
 }
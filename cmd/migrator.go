@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoChange - returned by Up/Down/Steps when there was nothing to apply
+// or revert.
+var ErrNoChange = errors.New("databasebreaker: no migration change")
+
+const (
+	defaultMigrationsTable = "schema_migrations"
+	defaultLockTable       = "schema_lock"
+)
+
+// Migration - a single ordered migration step.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigratorConfig - options for a Migrator.
+type MigratorConfig struct {
+	MigrationsTable string
+	LockTable       string
+
+	// ForceLock - if true, lock() reclaims an existing schema_lock row once
+	// it has aged past ForceLockAfter, on the assumption its owner crashed
+	// mid-run rather than still being alive. ForceLockAfter must be set (the
+	// zero value disables reclaiming entirely) so a live node's lock can
+	// never be stolen out from under it, which would let two nodes run
+	// migrations concurrently. This is not golang-migrate's manual "force"
+	// command; it only ever reclaims a row old enough to be stale.
+	ForceLock      bool
+	ForceLockAfter time.Duration
+
+	Backoff Backoff
+}
+
+// Migrator - applies ordered migrations against a DataStore, tracking the
+// applied version in MigrationsTable and serializing runners across nodes
+// via an INSERT-then-DELETE row in LockTable, which (unlike session
+// advisory locks) works on CockroachDB too.
+type Migrator struct {
+	ds         *DataStore
+	migrations []Migration
+	cfg        MigratorConfig
+	// now - overridable in tests so lock staleness can be exercised with a
+	// fake clock instead of real sleeps. Defaults to time.Now.
+	now func() time.Time
+}
+
+// Migrator - builds a Migrator running migrations against d.
+func (d *DataStore) Migrator(migrations []Migration, cfg MigratorConfig) *Migrator {
+	return NewMigrator(d, migrations, cfg)
+}
+
+// NewMigrator - builds a Migrator over ds from migrations, defaulting
+// MigrationsTable/LockTable/Backoff when cfg leaves them unset.
+func NewMigrator(ds *DataStore, migrations []Migration, cfg MigratorConfig) *Migrator {
+	if cfg.MigrationsTable == "" {
+		cfg.MigrationsTable = defaultMigrationsTable
+	}
+	if cfg.LockTable == "" {
+		cfg.LockTable = defaultLockTable
+	}
+	if cfg.Backoff == nil {
+		cfg.Backoff = NewExponentialBackoff()
+	}
+
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	return &Migrator{ds: ds, migrations: sorted, cfg: cfg, now: time.Now}
+}
+
+// MigrationsFromFS - loads ordered migrations from an fs.FS where each
+// version has a "NNN_name.up.sql" and optional "NNN_name.down.sql" file,
+// the same layout golang-migrate's file source uses.
+func MigrationsFromFS(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		version, name, direction, ok := parseMigrationFilename(entry.Name())
+		if !ok {
+			continue
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		switch direction {
+		case "up":
+			m.Up = string(content)
+		case "down":
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func parseMigrationFilename(filename string) (version int, name string, direction string, ok bool) {
+	base := strings.TrimSuffix(filename, ".sql")
+	parts := strings.SplitN(base, "_", 2)
+	if len(parts) != 2 {
+		return 0, "", "", false
+	}
+
+	v, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, "", "", false
+	}
+
+	switch {
+	case strings.HasSuffix(parts[1], ".up"):
+		return v, strings.TrimSuffix(parts[1], ".up"), "up", true
+	case strings.HasSuffix(parts[1], ".down"):
+		return v, strings.TrimSuffix(parts[1], ".down"), "down", true
+	default:
+		return 0, "", "", false
+	}
+}
+
+// Version - the currently applied migration version, or 0 if none have run.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	var version int
+	err := m.ds.Driver.QueryRow(ctx, fmt.Sprintf("SELECT version FROM %s LIMIT 1", m.cfg.MigrationsTable)).Scan(&version)
+	if errors.Is(err, ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// Up - applies all pending migrations, in order.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.apply(ctx, len(m.migrations))
+}
+
+// Down - reverts every applied migration, in reverse order.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.apply(ctx, -len(m.migrations))
+}
+
+// Steps - applies the next n pending migrations if n > 0, or reverts the
+// last -n applied migrations if n < 0.
+func (m *Migrator) Steps(ctx context.Context, n int) error {
+	return m.apply(ctx, n)
+}
+
+// apply - runs up to n pending migrations forward (n > 0) or backward
+// (n < 0), each inside its own transaction, serialized across nodes by the
+// schema lock.
+func (m *Migrator) apply(ctx context.Context, n int) error {
+	if err := m.ensureTables(ctx); err != nil {
+		return err
+	}
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.unlock(ctx)
+
+	current, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	if n >= 0 {
+		applied := 0
+		for _, mig := range m.migrations {
+			if applied >= n {
+				break
+			}
+			if mig.Version <= current {
+				continue
+			}
+			if err := m.runStep(ctx, mig, mig.Up, mig.Version); err != nil {
+				return err
+			}
+			applied++
+		}
+		if applied == 0 {
+			return ErrNoChange
+		}
+		return nil
+	}
+
+	reverted := 0
+	for i := len(m.migrations) - 1; i >= 0 && reverted < -n; i-- {
+		mig := m.migrations[i]
+		if mig.Version > current {
+			continue
+		}
+		prev := 0
+		if i > 0 {
+			prev = m.migrations[i-1].Version
+		}
+		if err := m.runStep(ctx, mig, mig.Down, prev); err != nil {
+			return err
+		}
+		current = prev
+		reverted++
+	}
+	if reverted == 0 {
+		return ErrNoChange
+	}
+	return nil
+}
+
+func (m *Migrator) runStep(ctx context.Context, mig Migration, script string, newVersion int) error {
+	tx, err := m.ds.Driver.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, script); err != nil {
+		tx.Rollback(ctx)
+		return fmt.Errorf("databasebreaker: migration %d (%s) failed: %w", mig.Version, mig.Name, err)
+	}
+	if err := m.setVersion(ctx, tx, newVersion); err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (m *Migrator) setVersion(ctx context.Context, tx Tx, version int) error {
+	if _, err := tx.Exec(ctx, fmt.Sprintf("DELETE FROM %s", m.cfg.MigrationsTable)); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, fmt.Sprintf("INSERT INTO %s (version) VALUES ($1)", m.cfg.MigrationsTable), version)
+	return err
+}
+
+func (m *Migrator) ensureTables(ctx context.Context) error {
+	if _, err := m.ds.Driver.Exec(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (version BIGINT NOT NULL)", m.cfg.MigrationsTable)); err != nil {
+		return err
+	}
+	_, err := m.ds.Driver.Exec(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (id INT PRIMARY KEY, locked BOOLEAN NOT NULL, locked_at TIMESTAMPTZ NOT NULL)", m.cfg.LockTable))
+	return err
+}
+
+// lockRowID - the schema_lock table only ever holds a single row, pinned
+// to this id so a second INSERT while the lock is held collides on the
+// primary key instead of silently succeeding.
+const lockRowID = 1
+
+// lock - serializes migrator runs across nodes using an INSERT-then-DELETE
+// row in LockTable. Retries with cfg.Backoff until the row can be
+// inserted, reclaiming the row when ForceLock is set and it has aged past
+// ForceLockAfter (presumed abandoned by a crashed migrator), or gives up
+// once the backoff policy is exhausted.
+func (m *Migrator) lock(ctx context.Context) error {
+	var attempt uint
+	for {
+		_, err := m.ds.Driver.Exec(ctx, fmt.Sprintf("INSERT INTO %s (id, locked, locked_at) VALUES ($1, true, $2)", m.cfg.LockTable), lockRowID, m.now())
+		if err == nil {
+			return nil
+		}
+
+		if m.cfg.ForceLock && m.cfg.ForceLockAfter > 0 {
+			if stale, staleErr := m.lockIsStale(ctx); staleErr == nil && stale {
+				if _, delErr := m.ds.Driver.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = $1", m.cfg.LockTable), lockRowID); delErr == nil {
+					continue
+				}
+			}
+		}
+
+		attempt++
+		wait := m.cfg.Backoff.NextInterval(attempt)
+		if wait < 0 {
+			return fmt.Errorf("databasebreaker: could not acquire migration lock: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// lockIsStale - reports whether the held schema_lock row is older than
+// ForceLockAfter, the only condition under which ForceLock is allowed to
+// reclaim it. Without this check a ForceLock node would delete another
+// node's legitimately-held lock out from under it and both would proceed
+// concurrently, defeating the serialization schema_lock exists to provide.
+func (m *Migrator) lockIsStale(ctx context.Context) (bool, error) {
+	var lockedAt time.Time
+	err := m.ds.Driver.QueryRow(ctx, fmt.Sprintf("SELECT locked_at FROM %s WHERE id = $1", m.cfg.LockTable), lockRowID).Scan(&lockedAt)
+	if err != nil {
+		return false, err
+	}
+	return m.now().Sub(lockedAt) > m.cfg.ForceLockAfter, nil
+}
+
+func (m *Migrator) unlock(ctx context.Context) error {
+	_, err := m.ds.Driver.Exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = $1", m.cfg.LockTable), lockRowID)
+	return err
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// migratorFakeDriver - a stateful in-process Driver tracking the
+// schema_migrations version and the schema_lock row, since InMemDriver's
+// QueryRow always returns ErrNoRows and can't back real version/lock
+// persistence.
+type migratorFakeDriver struct {
+	mu sync.Mutex
+
+	hasVersion bool
+	version    int
+
+	lockHeld bool
+	lockedAt time.Time
+
+	applied []string
+}
+
+func newMigratorFakeDriver() *migratorFakeDriver {
+	return &migratorFakeDriver{}
+}
+
+func (d *migratorFakeDriver) Begin(ctx context.Context) (Tx, error) {
+	return &migratorFakeTx{driver: d}, nil
+}
+
+func (d *migratorFakeDriver) Exec(ctx context.Context, sql string, args ...any) (any, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(sql, "CREATE TABLE IF NOT EXISTS") && (strings.Contains(sql, "schema_migrations") || strings.Contains(sql, "schema_lock")):
+		return nil, nil
+
+	case strings.Contains(sql, "INSERT INTO") && strings.Contains(sql, "schema_lock"):
+		if d.lockHeld {
+			return nil, errors.New("duplicate key value violates unique constraint")
+		}
+		d.lockHeld = true
+		if len(args) > 1 {
+			if t, ok := args[1].(time.Time); ok {
+				d.lockedAt = t
+			}
+		}
+		return nil, nil
+
+	case strings.Contains(sql, "DELETE FROM") && strings.Contains(sql, "schema_lock"):
+		d.lockHeld = false
+		return nil, nil
+
+	case strings.Contains(sql, "DELETE FROM") && strings.Contains(sql, "schema_migrations"):
+		d.hasVersion = false
+		return nil, nil
+
+	case strings.Contains(sql, "INSERT INTO") && strings.Contains(sql, "schema_migrations"):
+		if len(args) > 0 {
+			if v, ok := args[0].(int); ok {
+				d.version = v
+				d.hasVersion = true
+			}
+		}
+		return nil, nil
+
+	default:
+		d.applied = append(d.applied, sql)
+		return nil, nil
+	}
+}
+
+func (d *migratorFakeDriver) Query(ctx context.Context, sql string, args ...any) (any, error) {
+	return nil, nil
+}
+
+func (d *migratorFakeDriver) QueryRow(ctx context.Context, sql string, args ...any) RowScanner {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch {
+	case strings.Contains(sql, "schema_lock"):
+		if !d.lockHeld {
+			return migratorFakeRow{err: ErrNoRows}
+		}
+		return migratorFakeRow{val: d.lockedAt}
+
+	case strings.Contains(sql, "schema_migrations"):
+		if !d.hasVersion {
+			return migratorFakeRow{err: ErrNoRows}
+		}
+		return migratorFakeRow{val: d.version}
+
+	default:
+		return migratorFakeRow{err: ErrNoRows}
+	}
+}
+
+func (d *migratorFakeDriver) Ping(ctx context.Context) error { return nil }
+func (d *migratorFakeDriver) Close()                         {}
+
+type migratorFakeRow struct {
+	val any
+	err error
+}
+
+func (r migratorFakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	switch d := dest[0].(type) {
+	case *int:
+		*d = r.val.(int)
+	case *time.Time:
+		*d = r.val.(time.Time)
+	}
+	return nil
+}
+
+type migratorFakeTx struct {
+	driver *migratorFakeDriver
+}
+
+func (t *migratorFakeTx) Exec(ctx context.Context, sql string, args ...any) (any, error) {
+	return t.driver.Exec(ctx, sql, args...)
+}
+func (t *migratorFakeTx) Commit(ctx context.Context) error   { return nil }
+func (t *migratorFakeTx) Rollback(ctx context.Context) error { return nil }
+
+func testMigrations() []Migration {
+	return []Migration{
+		{Version: 1, Name: "widgets", Up: "CREATE TABLE widgets (id INT)", Down: "DROP TABLE widgets"},
+		{Version: 2, Name: "gadgets", Up: "CREATE TABLE gadgets (id INT)", Down: "DROP TABLE gadgets"},
+		{Version: 3, Name: "gizmos", Up: "CREATE TABLE gizmos (id INT)", Down: "DROP TABLE gizmos"},
+	}
+}
+
+func TestMigrator_UpDownSteps(t *testing.T) {
+	ds := &DataStore{Driver: newMigratorFakeDriver()}
+	m := NewMigrator(ds, testMigrations(), MigratorConfig{})
+
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if v, err := m.Version(context.Background()); err != nil || v != 3 {
+		t.Fatalf("Version after Up: got (%d, %v), want (3, nil)", v, err)
+	}
+
+	if err := m.Steps(context.Background(), -1); err != nil {
+		t.Fatalf("Steps(-1): %v", err)
+	}
+	if v, err := m.Version(context.Background()); err != nil || v != 2 {
+		t.Fatalf("Version after Steps(-1): got (%d, %v), want (2, nil)", v, err)
+	}
+
+	if err := m.Up(context.Background()); err != nil {
+		t.Fatalf("Up (reapply): %v", err)
+	}
+	if v, err := m.Version(context.Background()); err != nil || v != 3 {
+		t.Fatalf("Version after reapply: got (%d, %v), want (3, nil)", v, err)
+	}
+
+	if err := m.Down(context.Background()); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if v, err := m.Version(context.Background()); err != nil || v != 0 {
+		t.Fatalf("Version after Down: got (%d, %v), want (0, nil)", v, err)
+	}
+
+	if err := m.Down(context.Background()); !errors.Is(err, ErrNoChange) {
+		t.Fatalf("Down with nothing applied: got %v, want ErrNoChange", err)
+	}
+}
+
+func TestMigrator_ForceLock_RequiresStaleness(t *testing.T) {
+	driver := newMigratorFakeDriver()
+	ds := &DataStore{Driver: driver}
+	clock := newFakeClock()
+
+	fastBackoff := func() Backoff {
+		return &ExponentialBackoff{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond * 2, Multiplier: 1, MaxElapsedTime: time.Millisecond * 20}
+	}
+
+	holder := NewMigrator(ds, nil, MigratorConfig{})
+	holder.now = clock.now
+	if err := holder.lock(context.Background()); err != nil {
+		t.Fatalf("holder.lock: %v", err)
+	}
+
+	contender := NewMigrator(ds, nil, MigratorConfig{ForceLock: true, ForceLockAfter: time.Hour, Backoff: fastBackoff()})
+	contender.now = clock.now
+	if err := contender.lock(context.Background()); err == nil {
+		t.Fatal("expected ForceLock to be refused while the held lock is fresh")
+	}
+
+	clock.advance(time.Hour + time.Second)
+
+	reclaimer := NewMigrator(ds, nil, MigratorConfig{ForceLock: true, ForceLockAfter: time.Hour, Backoff: fastBackoff()})
+	reclaimer.now = clock.now
+	if err := reclaimer.lock(context.Background()); err != nil {
+		t.Fatalf("expected ForceLock to reclaim the lock once it aged past ForceLockAfter: %v", err)
+	}
+}
+
+func TestMigrator_Lock_WithoutForceLockNeverReclaims(t *testing.T) {
+	driver := newMigratorFakeDriver()
+	ds := &DataStore{Driver: driver}
+	clock := newFakeClock()
+
+	holder := NewMigrator(ds, nil, MigratorConfig{})
+	holder.now = clock.now
+	if err := holder.lock(context.Background()); err != nil {
+		t.Fatalf("holder.lock: %v", err)
+	}
+
+	clock.advance(24 * time.Hour)
+
+	contender := NewMigrator(ds, nil, MigratorConfig{
+		Backoff: &ExponentialBackoff{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond * 2, Multiplier: 1, MaxElapsedTime: time.Millisecond * 20},
+	})
+	contender.now = clock.now
+	if err := contender.lock(context.Background()); err == nil {
+		t.Fatal("expected lock() to never reclaim a held row when ForceLock is unset, however stale")
+	}
+}
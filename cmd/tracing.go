@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// redactDBURL - strips userinfo from a connection URL so it's safe to
+// attach to a span as db.url.
+func redactDBURL(dbUrl string) string {
+	u, err := url.Parse(dbUrl)
+	if err != nil {
+		return "redacted"
+	}
+	u.User = nil
+	return u.String()
+}
+
+// dbSystemFromURL - the OpenTelemetry db.system value for dbUrl's scheme.
+func dbSystemFromURL(dbUrl string) string {
+	u, err := url.Parse(dbUrl)
+	if err != nil {
+		return "unknown"
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "postgresql", "postgres":
+		return "postgresql"
+	case "cockroachdb":
+		return "cockroachdb"
+	case "mysql":
+		return "mysql"
+	case "inmem":
+		return "inmem"
+	default:
+		return u.Scheme
+	}
+}